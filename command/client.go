@@ -0,0 +1,17 @@
+package command
+
+import (
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+)
+
+// newClientset builds a Kubernetes clientset from the resolved kubeconfig,
+// context and cluster flags carried on cf.
+func newClientset(cf *genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+	cfg, err := cf.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return kubernetes.NewForConfig(cfg)
+}
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"hash/fnv"
+	"os"
+)
+
+// ansiReset clears any color set by ansiColors.
+const ansiReset = "\x1b[0m"
+
+// ansiColors is the palette prefixes are drawn from; a pod/container pair
+// always maps to the same entry via colorForKey.
+var ansiColors = []int{31, 32, 33, 34, 35, 36, 91, 92, 93, 94, 95, 96}
+
+// colorForKey derives a stable ANSI color code for key from an FNV hash so
+// repeated runs colorize the same (pod, container) pair identically.
+func colorForKey(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return ansiColors[h.Sum32()%uint32(len(ansiColors))]
+}
+
+// colorEnabled resolves the effective --color mode against the NO_COLOR
+// convention and whether out looks like a terminal.
+func colorEnabled(mode string, out *os.File) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	info, err := out.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -1,21 +1,24 @@
 package command
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"io"
+	"math"
 	"os"
-	"os/exec"
+	"os/signal"
 	"regexp"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
-	"github.com/spf13/pflag"
-
 	"github.com/spf13/cobra"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
 )
 
 // LogsOptions LogsOptions
@@ -29,25 +32,65 @@ type LogsOptions struct {
 	LimitBytes   int64
 	Tail         int64
 	Container    string
+	Watch        bool
+
+	// Multi-container / multi-stream display
+	AllContainers bool
+	IncludeInit   bool
+	Prefix        bool
+	NoPrefix      bool
+	Color         string
+
+	// Client-side filtering and output formatting
+	Grep    []string
+	Exclude []string
+	Output  string
+
+	// Splitting output to disk
+	OutputDir string
+	Split     string
+	MaxSize   string
+	MaxFiles  int
+	Tee       bool
 
 	// All
-	Namespace string
-	Debug     bool
+	Namespace     string
+	AllNamespaces bool
+	Selector      string
+	Debug         bool
+
+	args []string
+
+	configFlags *genericclioptions.ConfigFlags
+	clientset   kubernetes.Interface
+	pods        *Pods
+	sinceTime   *metav1.Time
+	processors  []LineProcessor
+	sinks       *sinkRegistry
 }
 
 // Usage Usage
-const Usage string = "kubelogs [-f] [-p] (POD | TYPE/NAME) [-c CONTAINER]"
+const Usage string = "kubelogs [-f] [-p] (POD | TYPE/NAME | -l label) [-c CONTAINER]"
 const logsExample = `kubelogs my-pod-v1
   kubelogs my-pod-v1 -c my-container
   kubelogs regex -f
   kubelogs my-pod-v1 --since 10m
-  kubelogs --tail 1`
+  kubelogs my-pod-v1 --tail 1
+  kubelogs deployment/my-deploy -f
+  kubelogs -l app=my-app
+  kubelogs svc/my-service -A`
 
 // NewLogsOptions NewLogsOptions
 func NewLogsOptions() *LogsOptions {
 	return &LogsOptions{
-		Tail:      -1,
-		Namespace: "default",
+		Tail:        -1,
+		Namespace:   "default",
+		Prefix:      true,
+		Color:       "auto",
+		Output:      "text",
+		Split:       "pod-container",
+		MaxFiles:    5,
+		configFlags: genericclioptions.NewConfigFlags(true),
 	}
 }
 
@@ -64,169 +107,349 @@ func NewCmdLogs() *cobra.Command {
 		Short:                 `Print the logs for a container in a pod`,
 		Long:                  `Print the logs for a container in a pod or specified resource. If the pod has only one container, the container name is optional.`,
 		Example:               logsExample,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if o.Debug {
 				logrus.SetLevel(logrus.TraceLevel)
 			}
 
-			fg := ""
-			cmd.Flags().VisitAll(func(flag *pflag.Flag) {
-				if flag.Name == "help" || flag.Name == "debug" || !flag.Changed {
-					return
-				}
-				fg += fmt.Sprintf(" --%s=%s", flag.Name, flag.Value)
-			})
-
-			pods := new(Pods)
-			for _, arg := range args {
-				p, err := cmdGetPods(arg, o.Container, o.Namespace)
-				if err != nil {
-					logrus.Fatalln(err)
-				}
-
-				pods.Items = append(pods.Items, p.Items...)
+			if err := o.Complete(args); err != nil {
+				return err
 			}
-
-			logrus.Infoln(fmt.Sprintf("kubelogs for %d pod", len(pods.Items)))
-
-			wg := &sync.WaitGroup{}
-			for _, pod := range pods.Items {
-				for _, container := range pod.Containers {
-					str := fmt.Sprintf("kubectl logs %s %s", pod.Name, fg)
-					if o.Container == "" {
-						str += fmt.Sprintf(" --container %s", container.Name)
-					}
-					logrus.Infoln(fmt.Sprintf("%s %s", pod.Name, container.Name))
-					logrus.Debugln(str)
-
-					prefix := fmt.Sprintf("[%s %s]", pod.Name, container.Name)
-
-					wg.Add(1)
-					go func(str, prefix string) {
-						defer wg.Done()
-
-						command := exec.Command("bash", "-c", str)
-						stdout, err := command.StdoutPipe()
-						if err != nil {
-							logrus.Errorln(err)
-							return
-						}
-						stderr, err := command.StderrPipe()
-						if err != nil {
-							logrus.Errorln(err)
-							return
-						}
-
-						if err := command.Start(); err != nil {
-							logrus.Errorln(err)
-							return
-						}
-
-						go func() {
-							reader := bufio.NewReader(stdout)
-
-							for {
-								line, err := reader.ReadString('\n')
-								if err != nil || io.EOF == err {
-									break
-								}
-								logrus.Infoln(prefix + line)
-							}
-						}()
-
-						go func() {
-							reader := bufio.NewReader(stderr)
-
-							for {
-								line, err := reader.ReadString('\n')
-								if err != nil || io.EOF == err {
-									break
-								}
-								logrus.Infoln(prefix + line)
-							}
-						}()
-
-						if err := command.Wait(); err != nil {
-							logrus.Errorln(err)
-							return
-						}
-
-						logrus.Infoln(prefix + "exit")
-					}(str, prefix)
-				}
+			if err := o.Validate(); err != nil {
+				return err
 			}
 
-			wg.Wait()
+			return o.Run()
 		},
 	}
 
 	cmd.Flags().BoolVarP(&o.Follow, "follow", "f", o.Follow, "Specify if the logs should be streamed.")
+	cmd.Flags().BoolVar(&o.Watch, "watch", o.Watch, "Keep streaming matching pods across restarts and rolling updates, reattaching as new pods/containers appear.")
 	cmd.Flags().BoolVar(&o.Timestamps, "timestamps", o.Timestamps, "Include timestamps on each line in the log output")
 	cmd.Flags().Int64Var(&o.LimitBytes, "limit-bytes", o.LimitBytes, "Maximum bytes of logs to return. Defaults to no limit.")
 	cmd.Flags().BoolVarP(&o.Previous, "previous", "p", o.Previous, "If true, print the logs for the previous instance of the container in a pod if it exists.")
-	cmd.Flags().Int64Var(&o.Tail, "tail", o.Tail, "Lines of recent log file to display. Showing all log lines otherwise 10, if a selector is provided.")
+	cmd.Flags().Int64Var(&o.Tail, "tail", o.Tail, "Lines of recent log file to display. Defaults to -1, showing all log lines, for every pod including ones matched by a selector.")
 	cmd.Flags().StringVar(&o.SinceTime, "since-time", o.SinceTime, "Only return logs after a specific date (RFC3339). Defaults to all logs. Only one of since-time / since may be used.")
 	cmd.Flags().DurationVar(&o.SinceSeconds, "since", o.SinceSeconds, "Only return logs newer than a relative duration like 5s, 2m, or 3h. Defaults to all logs. Only one of since-time / since may be used.")
 	cmd.Flags().StringVarP(&o.Container, "container", "c", o.Container, "Print the logs of this container")
-
-	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", o.Namespace, `The Kubernetes namespace where the pods are located`)
+	cmd.Flags().BoolVar(&o.AllContainers, "all-containers", o.AllContainers, "Get all containers' logs, overriding --container.")
+	cmd.Flags().BoolVar(&o.IncludeInit, "include-init", o.IncludeInit, "Include init and ephemeral containers when streaming logs.")
+	cmd.Flags().BoolVar(&o.Prefix, "prefix", o.Prefix, "Prefix each log line with the pod and container it came from.")
+	cmd.Flags().BoolVar(&o.NoPrefix, "no-prefix", o.NoPrefix, "Disable the pod/container log line prefix.")
+	cmd.Flags().StringVar(&o.Color, "color", o.Color, "Colorize the log line prefix: auto, always, or never.")
+	cmd.Flags().StringArrayVar(&o.Grep, "grep", o.Grep, "Only print lines matching this regexp. Can be repeated.")
+	cmd.Flags().StringArrayVar(&o.Exclude, "exclude", o.Exclude, "Drop lines matching this regexp. Can be repeated.")
+	cmd.Flags().StringVar(&o.Output, "output", o.Output, "Output format: text, json, or logfmt.")
+
+	cmd.Flags().StringVar(&o.OutputDir, "output-dir", o.OutputDir, "Write each stream to a file under this directory instead of (or, with --tee, as well as) the terminal.")
+	cmd.Flags().StringVar(&o.Split, "split", o.Split, "How to group streams into files when --output-dir is set: pod, container, or pod-container.")
+	cmd.Flags().StringVar(&o.MaxSize, "max-size", o.MaxSize, "Rotate a split output file before a write would take it past this size, e.g. 100MB. Defaults to no rotation.")
+	cmd.Flags().IntVar(&o.MaxFiles, "max-files", o.MaxFiles, "Number of rotated files to keep per split output file.")
+	cmd.Flags().BoolVar(&o.Tee, "tee", o.Tee, "With --output-dir, also print lines to the terminal.")
+
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", o.AllNamespaces, `If true, match pods across all namespaces`)
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, `Selector (label query) to filter on, e.g. -l key1=value1,key2=value2`)
 	cmd.Flags().BoolVarP(&o.Debug, "debug", "v", o.Debug, `Debug tool`)
 
+	o.configFlags.AddFlags(cmd.Flags())
+
 	return cmd
 }
 
-func cmdGetPods(podRegexp, cName, namespace string) (pods *Pods, err error) {
-	cmd := exec.Command("bash", "-c", fmt.Sprintf(`kubectl get pod -n %s --output=jsonpath="{range .items[*]}{.metadata.name} {.spec['containers', 'initContainers'][*].name}|{end}"`, namespace))
-	out, err := cmd.CombinedOutput()
+// Complete fills in the fields that require the parsed args and builds the
+// Kubernetes clientset used by Run.
+func (o *LogsOptions) Complete(args []string) error {
+	o.args = args
+
+	if ns := o.configFlags.Namespace; ns != nil && *ns != "" {
+		o.Namespace = *ns
+	}
+
+	clientset, err := newClientset(o.configFlags)
 	if err != nil {
-		return
+		return fmt.Errorf("building kubernetes client: %w", err)
+	}
+	o.clientset = clientset
+
+	if o.SinceTime != "" {
+		t, err := ParseRFC3339(o.SinceTime)
+		if err != nil {
+			return err
+		}
+		o.sinceTime = t
 	}
 
-	pr, err := regexp.Compile(podRegexp)
+	processors, err := o.buildProcessors()
 	if err != nil {
-		logrus.Fatalln(err)
+		return err
 	}
+	o.processors = processors
 
-	pods = new(Pods)
+	if o.OutputDir != "" {
+		maxSize, err := parseSize(o.MaxSize)
+		if err != nil {
+			return err
+		}
+		o.sinks = newSinkRegistry(maxSize, o.MaxFiles)
+	}
+
+	return nil
+}
 
-	ps := strings.Split(string(out), "|")
-	for _, p := range ps {
-		cs := strings.Split(string(p), " ")
-		if !pr.MatchString(cs[0]) {
-			continue
+// buildProcessors compiles --grep/--exclude into filters and appends the
+// formatter selected by --output, in that order.
+func (o *LogsOptions) buildProcessors() ([]LineProcessor, error) {
+	var procs []LineProcessor
+
+	for _, pattern := range o.Grep {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --grep %q: %w", pattern, err)
 		}
+		procs = append(procs, &grepProcessor{re: re})
+	}
 
-		pod := &Pod{
-			Name:       cs[0],
-			Containers: make([]*Container, 0, len(cs[1:])),
+	for _, pattern := range o.Exclude {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --exclude %q: %w", pattern, err)
 		}
-		for _, c := range cs[1:] {
-			if cName != "" && cName != c {
-				continue
-			}
-			pod.Containers = append(pod.Containers, &Container{
-				Name: c,
-			})
+		procs = append(procs, &excludeProcessor{re: re})
+	}
+
+	switch o.Output {
+	case "", "text":
+		procs = append(procs, &textFormatter{})
+	case "json":
+		procs = append(procs, &jsonFormatter{timestamps: o.Timestamps})
+	case "logfmt":
+		procs = append(procs, &logfmtFormatter{timestamps: o.Timestamps})
+	default:
+		return nil, fmt.Errorf("invalid --output %q: must be text, json, or logfmt", o.Output)
+	}
+
+	return procs, nil
+}
+
+// Validate checks that the options are internally consistent.
+func (o *LogsOptions) Validate() error {
+	if len(o.args) == 0 && o.Selector == "" {
+		return fmt.Errorf("a pod name, resource, or selector is required")
+	}
+
+	if o.SinceTime != "" && o.SinceSeconds != 0 {
+		return fmt.Errorf("only one of --since-time / --since may be used")
+	}
+
+	switch o.Color {
+	case "auto", "always", "never":
+	default:
+		return fmt.Errorf("invalid --color %q: must be auto, always, or never", o.Color)
+	}
+
+	if o.Watch {
+		o.Follow = true
+	}
+
+	switch o.Split {
+	case "pod", "container", "pod-container":
+	default:
+		return fmt.Errorf("invalid --split %q: must be pod, container, or pod-container", o.Split)
+	}
+
+	return nil
+}
+
+// effectivePrefix reports whether log lines should be prefixed, honoring
+// --no-prefix overriding --prefix. Structured output formats carry
+// namespace/pod/container as record fields, so the bracket prefix is
+// suppressed for them regardless of --prefix.
+func (o *LogsOptions) effectivePrefix() bool {
+	if o.Output != "" && o.Output != "text" {
+		return false
+	}
+	return o.Prefix && !o.NoPrefix
+}
+
+// containerName returns the container filter to resolve pods with,
+// clearing it when --all-containers overrides --container.
+func (o *LogsOptions) containerName() string {
+	if o.AllContainers {
+		return ""
+	}
+	return o.Container
+}
+
+// containerLabel builds the identifying "pod container" label for a
+// stream, including the container kind when --include-init is set.
+func (o *LogsOptions) containerLabel(pod *Pod, container *Container) string {
+	if o.IncludeInit {
+		kind := container.Kind
+		if kind == "" {
+			kind = "main"
 		}
+		return fmt.Sprintf("%s %s:%s", pod.Name, container.Name, kind)
+	}
+	return fmt.Sprintf("%s %s", pod.Name, container.Name)
+}
 
-		pods.Items = append(pods.Items, pod)
+// containerPrefix builds the "[pod container]" prefix for a stream,
+// colorizing it when colorsOn is true.
+func (o *LogsOptions) containerPrefix(pod *Pod, container *Container, colorsOn bool) string {
+	if !o.effectivePrefix() {
+		return ""
 	}
 
-	return
+	label := o.containerLabel(pod, container)
+
+	if !colorsOn {
+		return "[" + label + "]"
+	}
+
+	code := colorForKey(pod.Name + "/" + container.Name)
+	return fmt.Sprintf("\x1b[%dm[%s]%s", code, label, ansiReset)
+}
+
+// diskPrefix returns the "[pod container]" label to prepend to lines
+// written to disk, or "" when it isn't needed: structured output formats
+// (json/logfmt) already carry namespace/pod/container per record, and the
+// default pod-container split gives every stream its own file. Every other
+// split groups multiple pods/containers into one file, so without this the
+// on-disk lines would be unattributable.
+func (o *LogsOptions) diskPrefix(pod *Pod, container *Container) string {
+	if o.Split == "pod-container" {
+		return ""
+	}
+	if o.Output != "" && o.Output != "text" {
+		return ""
+	}
+	return "[" + o.containerLabel(pod, container) + "]"
 }
 
-// Pods Pods
-type Pods struct {
-	Items []*Pod
+// diskWriter returns the rotating file writer for a (pod, container)
+// stream when --output-dir is set, or a nil io.Writer otherwise.
+func (o *LogsOptions) diskWriter(pod *Pod, container *Container) (io.Writer, error) {
+	if o.sinks == nil {
+		return nil, nil
+	}
+
+	path := splitPath(o.OutputDir, o.Split, pod.Namespace, pod.Name, container.Name)
+	w, err := o.sinks.get(path)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
 }
 
-// Pod Pod
-type Pod struct {
-	Name       string
-	Containers []*Container
+// namespace returns the namespace to list against, honoring
+// --all-namespaces.
+func (o *LogsOptions) namespace() string {
+	if o.AllNamespaces {
+		return metav1.NamespaceAll
+	}
+	return o.Namespace
 }
 
-// Container Container
-type Container struct {
-	Name string
+// Run resolves the requested pods and streams their container logs.
+func (o *LogsOptions) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if o.sinks != nil {
+		defer o.sinks.Close()
+	}
+
+	if o.Watch {
+		return o.runWatch(ctx)
+	}
+
+	ns := o.namespace()
+	cName := o.containerName()
+
+	pods := new(Pods)
+
+	if o.Selector != "" {
+		p, err := resolvePodsBySelector(ctx, o.clientset, ns, o.Selector, cName, o.IncludeInit)
+		if err != nil {
+			return err
+		}
+		pods.Items = append(pods.Items, p.Items...)
+	}
+
+	for _, arg := range o.args {
+		p, err := resolveTarget(ctx, o.clientset, arg, cName, ns, o.IncludeInit)
+		if err != nil {
+			return err
+		}
+
+		pods.Items = append(pods.Items, p.Items...)
+	}
+	o.pods = pods
+
+	logrus.Infoln(fmt.Sprintf("kubelogs for %d pod", len(pods.Items)))
+
+	colorsOn := colorEnabled(o.Color, os.Stdout)
+	writer := newPrefixWriter(os.Stdout)
+
+	wg := &sync.WaitGroup{}
+	for _, pod := range pods.Items {
+		for _, container := range pod.Containers {
+			disk, err := o.diskWriter(pod, container)
+			if err != nil {
+				return err
+			}
+
+			target := &streamTarget{
+				Pod:        pod,
+				Container:  container,
+				Opts:       o.podLogOptions(container.Name),
+				Prefix:     o.containerPrefix(pod, container, colorsOn),
+				Processors: o.processors,
+				Disk:       disk,
+				Tee:        o.Tee,
+				DiskPrefix: o.diskPrefix(pod, container),
+			}
+
+			logrus.Infoln(pod.Name, container.Name)
+
+			wg.Add(1)
+			go func(target *streamTarget) {
+				defer wg.Done()
+				streamContainer(ctx, o.clientset, target, writer)
+			}(target)
+		}
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// podLogOptions builds the corev1.PodLogOptions for containerName, only
+// setting fields that differ from the API defaults so the request matches
+// what upstream kubectl would send.
+func (o *LogsOptions) podLogOptions(containerName string) *corev1.PodLogOptions {
+	opts := &corev1.PodLogOptions{
+		Container:  containerName,
+		Follow:     o.Follow,
+		Previous:   o.Previous,
+		Timestamps: o.Timestamps,
+	}
+
+	if o.sinceTime != nil {
+		opts.SinceTime = o.sinceTime
+	} else if o.SinceSeconds != 0 {
+		sec := int64(math.Ceil(o.SinceSeconds.Seconds()))
+		opts.SinceSeconds = &sec
+	}
+
+	if o.LimitBytes != 0 {
+		opts.LimitBytes = &o.LimitBytes
+	}
+
+	if o.Tail >= 0 {
+		opts.TailLines = &o.Tail
+	}
+
+	return opts
 }
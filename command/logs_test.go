@@ -0,0 +1,74 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodLogOptionsDefaults(t *testing.T) {
+	o := NewLogsOptions()
+
+	opts := o.podLogOptions("my-container")
+
+	if opts.Container != "my-container" {
+		t.Errorf("Container = %q, want %q", opts.Container, "my-container")
+	}
+	if opts.Follow || opts.Previous || opts.Timestamps {
+		t.Errorf("expected Follow/Previous/Timestamps to default false, got %+v", opts)
+	}
+	if opts.SinceTime != nil {
+		t.Errorf("SinceTime = %v, want nil", opts.SinceTime)
+	}
+	if opts.SinceSeconds != nil {
+		t.Errorf("SinceSeconds = %v, want nil", opts.SinceSeconds)
+	}
+	if opts.LimitBytes != nil {
+		t.Errorf("LimitBytes = %v, want nil", opts.LimitBytes)
+	}
+	if opts.TailLines != nil {
+		t.Errorf("TailLines = %v, want nil for the default Tail=-1", opts.TailLines)
+	}
+}
+
+func TestPodLogOptionsSetFields(t *testing.T) {
+	o := NewLogsOptions()
+	o.Follow = true
+	o.Previous = true
+	o.Timestamps = true
+	o.SinceSeconds = 90 * time.Second
+	o.LimitBytes = 1024
+	o.Tail = 50
+
+	opts := o.podLogOptions("my-container")
+
+	if !opts.Follow || !opts.Previous || !opts.Timestamps {
+		t.Errorf("expected Follow/Previous/Timestamps true, got %+v", opts)
+	}
+	if opts.SinceSeconds == nil || *opts.SinceSeconds != 90 {
+		t.Errorf("SinceSeconds = %v, want 90", opts.SinceSeconds)
+	}
+	if opts.LimitBytes == nil || *opts.LimitBytes != 1024 {
+		t.Errorf("LimitBytes = %v, want 1024", opts.LimitBytes)
+	}
+	if opts.TailLines == nil || *opts.TailLines != 50 {
+		t.Errorf("TailLines = %v, want 50", opts.TailLines)
+	}
+}
+
+func TestPodLogOptionsSinceTimeTakesPrecedence(t *testing.T) {
+	o := NewLogsOptions()
+	o.SinceSeconds = 30 * time.Second
+	t0 := metav1.NewTime(time.Unix(0, 0))
+	o.sinceTime = &t0
+
+	opts := o.podLogOptions("my-container")
+
+	if opts.SinceTime == nil || !opts.SinceTime.Equal(&t0) {
+		t.Errorf("SinceTime = %v, want %v", opts.SinceTime, t0)
+	}
+	if opts.SinceSeconds != nil {
+		t.Errorf("SinceSeconds = %v, want nil when SinceTime is set", opts.SinceSeconds)
+	}
+}
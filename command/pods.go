@@ -0,0 +1,91 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Pods Pods
+type Pods struct {
+	Items []*Pod
+}
+
+// Pod Pod
+type Pod struct {
+	Name       string
+	Namespace  string
+	Containers []*Container
+}
+
+// Container Container
+type Container struct {
+	Name string
+	// Kind is "" for a regular container, or "init"/"ephemeral".
+	Kind string
+}
+
+// resolvePods lists the pods in namespace and keeps the ones whose name
+// matches podRegexp, restricting each pod's containers to cName when set.
+func resolvePods(ctx context.Context, clientset kubernetes.Interface, podRegexp, cName, namespace string, includeInit bool) (*Pods, error) {
+	pr, err := regexp.Compile(podRegexp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod expression %q: %w", podRegexp, err)
+	}
+
+	list, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	pods := new(Pods)
+	for i := range list.Items {
+		p := &list.Items[i]
+		if !pr.MatchString(p.Name) {
+			continue
+		}
+
+		pods.Items = append(pods.Items, podFromAPI(p, cName, includeInit))
+	}
+
+	return pods, nil
+}
+
+// podFromAPI converts a corev1.Pod into the command package's Pod type,
+// restricting the container list to cName when it is non-empty and adding
+// init/ephemeral containers when includeInit is set.
+func podFromAPI(p *corev1.Pod, cName string, includeInit bool) *Pod {
+	pod := &Pod{
+		Name:      p.Name,
+		Namespace: p.Namespace,
+	}
+
+	addContainers(pod, p.Spec.Containers, "", cName)
+
+	if includeInit {
+		addContainers(pod, p.Spec.InitContainers, "init", cName)
+
+		ephemeral := make([]corev1.Container, 0, len(p.Spec.EphemeralContainers))
+		for _, c := range p.Spec.EphemeralContainers {
+			ephemeral = append(ephemeral, corev1.Container(c.EphemeralContainerCommon))
+		}
+		addContainers(pod, ephemeral, "ephemeral", cName)
+	}
+
+	return pod
+}
+
+// addContainers appends cs to pod.Containers tagged with kind, skipping any
+// whose name doesn't match cName when cName is non-empty.
+func addContainers(pod *Pod, cs []corev1.Container, kind, cName string) {
+	for _, c := range cs {
+		if cName != "" && cName != c.Name {
+			continue
+		}
+		pod.Containers = append(pod.Containers, &Container{Name: c.Name, Kind: kind})
+	}
+}
@@ -0,0 +1,126 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LogRecord is the data streamContainer gathers for a single log line
+// before handing it to the processor chain.
+type LogRecord struct {
+	Timestamp string
+	Namespace string
+	Pod       string
+	Container string
+	Message   string
+	Rendered  string
+}
+
+// LineProcessor inspects or transforms a LogRecord. Returning false drops
+// the line before it reaches the shared writer. Filters (grep/exclude)
+// and formatters (text/json/logfmt) are both LineProcessors, chained in
+// order, so new formats plug in without touching the streaming code.
+type LineProcessor interface {
+	Process(rec *LogRecord) bool
+}
+
+// grepProcessor keeps only lines whose message matches re.
+type grepProcessor struct{ re *regexp.Regexp }
+
+func (p *grepProcessor) Process(rec *LogRecord) bool {
+	return p.re.MatchString(rec.Message)
+}
+
+// excludeProcessor drops lines whose message matches re.
+type excludeProcessor struct{ re *regexp.Regexp }
+
+func (p *excludeProcessor) Process(rec *LogRecord) bool {
+	return !p.re.MatchString(rec.Message)
+}
+
+// kubeletTimestamp matches the RFC3339(Nano) timestamp kubelet prepends to
+// each line when --timestamps is set.
+var kubeletTimestamp = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})) (.*)$`)
+
+// splitTimestamp separates a kubelet-prepended timestamp from the rest of
+// the line, if present.
+func splitTimestamp(line string) (timestamp, rest string, ok bool) {
+	m := kubeletTimestamp.FindStringSubmatch(line)
+	if m == nil {
+		return "", line, false
+	}
+	return m[1], m[2], true
+}
+
+// textFormatter renders the message unchanged; the pod/container prefix is
+// added separately by the shared writer.
+type textFormatter struct{}
+
+func (f *textFormatter) Process(rec *LogRecord) bool {
+	rec.Rendered = rec.Message
+	return true
+}
+
+// jsonFormatter renders rec as a single-line JSON record, promoting the
+// kubelet timestamp out of the message when present.
+type jsonFormatter struct{ timestamps bool }
+
+func (f *jsonFormatter) Process(rec *LogRecord) bool {
+	message := rec.Message
+	timestamp := ""
+	if f.timestamps {
+		if ts, rest, ok := splitTimestamp(message); ok {
+			timestamp, message = ts, rest
+		}
+	}
+
+	b, err := json.Marshal(struct {
+		Timestamp string `json:"timestamp,omitempty"`
+		Namespace string `json:"namespace"`
+		Pod       string `json:"pod"`
+		Container string `json:"container"`
+		Message   string `json:"message"`
+	}{timestamp, rec.Namespace, rec.Pod, rec.Container, message})
+	if err != nil {
+		rec.Rendered = rec.Message
+		return true
+	}
+
+	rec.Rendered = string(b)
+	return true
+}
+
+// logfmtFormatter renders rec as key=value pairs, quoting values that
+// contain spaces, tabs or double quotes.
+type logfmtFormatter struct{ timestamps bool }
+
+func (f *logfmtFormatter) Process(rec *LogRecord) bool {
+	message := rec.Message
+	timestamp := ""
+	if f.timestamps {
+		if ts, rest, ok := splitTimestamp(message); ok {
+			timestamp, message = ts, rest
+		}
+	}
+
+	var b strings.Builder
+	if timestamp != "" {
+		fmt.Fprintf(&b, "timestamp=%s ", logfmtValue(timestamp))
+	}
+	fmt.Fprintf(&b, "namespace=%s pod=%s container=%s message=%s",
+		logfmtValue(rec.Namespace), logfmtValue(rec.Pod), logfmtValue(rec.Container), logfmtValue(message))
+
+	rec.Rendered = b.String()
+	return true
+}
+
+// logfmtValue quotes v (Go-escaped, via %q) when it contains a space, tab
+// or double quote so logfmt consumers can still split on whitespace.
+func logfmtValue(v string) string {
+	if strings.ContainsAny(v, " \t\"") {
+		return fmt.Sprintf("%q", v)
+	}
+	return v
+}
@@ -0,0 +1,44 @@
+package command
+
+import "testing"
+
+func TestSplitTimestamp(t *testing.T) {
+	cases := []struct {
+		line          string
+		wantTimestamp string
+		wantRest      string
+		wantOK        bool
+	}{
+		{"2024-01-02T03:04:05Z hello world", "2024-01-02T03:04:05Z", "hello world", true},
+		{"2024-01-02T03:04:05.123456789+02:00 hello", "2024-01-02T03:04:05.123456789+02:00", "hello", true},
+		{"no timestamp here", "", "no timestamp here", false},
+		{"", "", "", false},
+	}
+
+	for _, c := range cases {
+		ts, rest, ok := splitTimestamp(c.line)
+		if ok != c.wantOK || ts != c.wantTimestamp || rest != c.wantRest {
+			t.Errorf("splitTimestamp(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				c.line, ts, rest, ok, c.wantTimestamp, c.wantRest, c.wantOK)
+		}
+	}
+}
+
+func TestLogfmtValue(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"has space", `"has space"`},
+		{"has\ttab", `"has\ttab"`},
+		{`has"quote`, `"has\"quote"`},
+		{"", ""},
+	}
+
+	for _, c := range cases {
+		if got := logfmtValue(c.in); got != c.want {
+			t.Errorf("logfmtValue(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+var resourceRefPattern = regexp.MustCompile(`^([a-zA-Z]+)/(.+)$`)
+
+// resourceRef is a parsed TYPE/NAME target, e.g. "deployment/foo".
+type resourceRef struct {
+	Kind string
+	Name string
+}
+
+// resourceKinds normalizes the short and long forms accepted on the
+// command line to the kind resolveTarget and selectorForResource switch on.
+var resourceKinds = map[string]string{
+	"deployment":  "deployment",
+	"deploy":      "deployment",
+	"statefulset": "statefulset",
+	"sts":         "statefulset",
+	"daemonset":   "daemonset",
+	"ds":          "daemonset",
+	"replicaset":  "replicaset",
+	"rs":          "replicaset",
+	"service":     "service",
+	"svc":         "service",
+	"job":         "job",
+	"cronjob":     "cronjob",
+	"cj":          "cronjob",
+}
+
+// parseResourceRef reports whether arg has the TYPE/NAME shape and, if so,
+// returns the normalized kind and name.
+func parseResourceRef(arg string) (*resourceRef, bool) {
+	m := resourceRefPattern.FindStringSubmatch(arg)
+	if m == nil {
+		return nil, false
+	}
+
+	kind, ok := resourceKinds[m[1]]
+	if !ok {
+		return nil, false
+	}
+
+	return &resourceRef{Kind: kind, Name: m[2]}, true
+}
+
+// resolveTarget turns a single command-line argument into the pods it
+// refers to: a TYPE/NAME resource, or else a bare pod-name regex.
+func resolveTarget(ctx context.Context, clientset kubernetes.Interface, arg, cName, namespace string, includeInit bool) (*Pods, error) {
+	ref, ok := parseResourceRef(arg)
+	if !ok {
+		return resolvePods(ctx, clientset, arg, cName, namespace, includeInit)
+	}
+
+	if ref.Kind == "cronjob" {
+		return resolvePodsForCronJob(ctx, clientset, namespace, ref.Name, cName, includeInit)
+	}
+
+	selector, err := selectorForResource(ctx, clientset, namespace, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolvePodsBySelector(ctx, clientset, namespace, selector.String(), cName, includeInit)
+}
+
+// selectorForResource resolves the label selector that governs ref's pods.
+func selectorForResource(ctx context.Context, clientset kubernetes.Interface, namespace string, ref *resourceRef) (labels.Selector, error) {
+	switch ref.Kind {
+	case "deployment":
+		d, err := clientset.AppsV1().Deployments(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return selectorFromLabelSelector(d.Spec.Selector)
+
+	case "statefulset":
+		s, err := clientset.AppsV1().StatefulSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return selectorFromLabelSelector(s.Spec.Selector)
+
+	case "daemonset":
+		d, err := clientset.AppsV1().DaemonSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return selectorFromLabelSelector(d.Spec.Selector)
+
+	case "replicaset":
+		r, err := clientset.AppsV1().ReplicaSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return selectorFromLabelSelector(r.Spec.Selector)
+
+	case "service":
+		svc, err := clientset.CoreV1().Services(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if len(svc.Spec.Selector) == 0 {
+			return nil, fmt.Errorf("service %q has no selector", ref.Name)
+		}
+		return labels.SelectorFromSet(svc.Spec.Selector), nil
+
+	case "job":
+		j, err := clientset.BatchV1().Jobs(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if j.Spec.Selector != nil {
+			return selectorFromLabelSelector(j.Spec.Selector)
+		}
+		return labels.SelectorFromSet(map[string]string{"job-name": j.Name}), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported resource kind %q", ref.Kind)
+	}
+}
+
+func selectorFromLabelSelector(sel *metav1.LabelSelector) (labels.Selector, error) {
+	if sel == nil {
+		return nil, fmt.Errorf("resource has no label selector")
+	}
+	return metav1.LabelSelectorAsSelector(sel)
+}
+
+// resolvePodsForCronJob finds the Jobs owned by the CronJob through their
+// owner references, then each Job's pods through its label selector, since
+// a CronJob has no selector of its own.
+func resolvePodsForCronJob(ctx context.Context, clientset kubernetes.Interface, namespace, name, cName string, includeInit bool) (*Pods, error) {
+	cj, err := clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	pods := new(Pods)
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if !ownedByCronJob(job.OwnerReferences, cj.UID) {
+			continue
+		}
+
+		selector := labels.SelectorFromSet(map[string]string{"job-name": job.Name})
+		if job.Spec.Selector != nil {
+			if s, err := selectorFromLabelSelector(job.Spec.Selector); err == nil {
+				selector = s
+			}
+		}
+
+		p, err := resolvePodsBySelector(ctx, clientset, namespace, selector.String(), cName, includeInit)
+		if err != nil {
+			return nil, err
+		}
+		pods.Items = append(pods.Items, p.Items...)
+	}
+
+	return pods, nil
+}
+
+// ownedByCronJob reports whether refs contains an owning CronJob with the
+// given UID.
+func ownedByCronJob(refs []metav1.OwnerReference, uid types.UID) bool {
+	for _, r := range refs {
+		if r.Kind == "CronJob" && r.UID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePodsBySelector lists pods matching a label selector, restricting
+// each pod's containers to cName when set.
+func resolvePodsBySelector(ctx context.Context, clientset kubernetes.Interface, namespace, selector, cName string, includeInit bool) (*Pods, error) {
+	list, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	pods := new(Pods)
+	for i := range list.Items {
+		pods.Items = append(pods.Items, podFromAPI(&list.Items[i], cName, includeInit))
+	}
+
+	return pods, nil
+}
@@ -0,0 +1,91 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseResourceRef(t *testing.T) {
+	cases := []struct {
+		arg      string
+		wantKind string
+		wantName string
+		wantOK   bool
+	}{
+		{"deployment/foo", "deployment", "foo", true},
+		{"deploy/foo", "deployment", "foo", true},
+		{"sts/foo", "statefulset", "foo", true},
+		{"svc/my-service", "service", "my-service", true},
+		{"cj/nightly", "cronjob", "nightly", true},
+		{"my-pod-v1", "", "", false},
+		{"bogus/foo", "", "", false},
+		{"/foo", "", "", false},
+	}
+
+	for _, c := range cases {
+		ref, ok := parseResourceRef(c.arg)
+		if ok != c.wantOK {
+			t.Errorf("parseResourceRef(%q) ok = %v, want %v", c.arg, ok, c.wantOK)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if ref.Kind != c.wantKind || ref.Name != c.wantName {
+			t.Errorf("parseResourceRef(%q) = %+v, want {%s %s}", c.arg, ref, c.wantKind, c.wantName)
+		}
+	}
+}
+
+func TestSelectorForResource(t *testing.T) {
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-deploy", Namespace: "ns"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "my-app"}},
+		},
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-service", Namespace: "ns"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "svc-app"}},
+	}
+	svcNoSelector := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "headless", Namespace: "ns"},
+	}
+
+	clientset := fake.NewSimpleClientset(deploy, svc, svcNoSelector)
+
+	cases := []struct {
+		name    string
+		ref     *resourceRef
+		want    string
+		wantErr bool
+	}{
+		{"deployment", &resourceRef{Kind: "deployment", Name: "my-deploy"}, "app=my-app", false},
+		{"service", &resourceRef{Kind: "service", Name: "my-service"}, "app=svc-app", false},
+		{"service without selector", &resourceRef{Kind: "service", Name: "headless"}, "", true},
+		{"missing resource", &resourceRef{Kind: "deployment", Name: "nope"}, "", true},
+		{"unsupported kind", &resourceRef{Kind: "pod", Name: "my-pod"}, "", true},
+	}
+
+	for _, c := range cases {
+		sel, err := selectorForResource(context.Background(), clientset, "ns", c.ref)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got selector %v", c.name, sel)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+		if sel.String() != c.want {
+			t.Errorf("%s: selector = %q, want %q", c.name, sel.String(), c.want)
+		}
+	}
+}
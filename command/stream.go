@@ -0,0 +1,137 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// prefixWriter serializes writes from concurrent container streams so two
+// goroutines never interleave mid-line.
+type prefixWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func newPrefixWriter(out io.Writer) *prefixWriter {
+	return &prefixWriter{out: out}
+}
+
+// WriteLine writes a single log line given without its trailing newline,
+// prepending prefix when non-empty and appending the newline.
+func (w *prefixWriter) WriteLine(prefix, line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if prefix == "" {
+		fmt.Fprintln(w.out, line)
+		return
+	}
+	fmt.Fprintln(w.out, prefix+" "+line)
+}
+
+// streamTarget is everything streamContainer needs to know about one
+// (pod, container) log stream.
+type streamTarget struct {
+	Pod        *Pod
+	Container  *Container
+	Opts       *corev1.PodLogOptions
+	Prefix     string
+	Processors []LineProcessor
+
+	// Disk is set when --output-dir is in effect; lines go there instead
+	// of the terminal writer w, unless Tee is also set.
+	Disk io.Writer
+	Tee  bool
+
+	// DiskPrefix identifies the source of a line written to Disk, for
+	// --split modes where several streams share one file. Empty when the
+	// file is already exclusive to this stream or the format carries its
+	// own attribution.
+	DiskPrefix string
+}
+
+// streamContainer opens the log stream for target, runs each line through
+// target.Processors, and writes whatever survives to w (and/or target.Disk)
+// until the stream closes or ctx is cancelled.
+func streamContainer(ctx context.Context, clientset kubernetes.Interface, target *streamTarget, w *prefixWriter) {
+	req := clientset.CoreV1().Pods(target.Pod.Namespace).GetLogs(target.Pod.Name, target.Opts)
+
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		logrus.Errorln(target.Prefix, err)
+		return
+	}
+	defer stream.Close()
+
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadString('\n')
+		if text := strings.TrimRight(line, "\n"); text != "" {
+			if out, ok := target.process(text); ok {
+				target.write(w, out)
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				logrus.Errorln(target.Prefix, err)
+			}
+			break
+		}
+	}
+
+	if target.Disk != nil {
+		logrus.Infoln(target.Prefix, "exit")
+	} else {
+		logrus.Debugln(target.Prefix, "exit")
+	}
+}
+
+// write sends a rendered line to disk (when configured) and/or the
+// terminal writer w, per Tee.
+func (target *streamTarget) write(w *prefixWriter, line string) {
+	if target.Disk == nil {
+		w.WriteLine(target.Prefix, line)
+		return
+	}
+
+	if target.DiskPrefix == "" {
+		fmt.Fprintln(target.Disk, line)
+	} else {
+		fmt.Fprintln(target.Disk, target.DiskPrefix+" "+line)
+	}
+
+	if target.Tee {
+		w.WriteLine(target.Prefix, line)
+	}
+}
+
+// process runs message through target.Processors, returning the rendered
+// line and false if any processor dropped it.
+func (target *streamTarget) process(message string) (string, bool) {
+	rec := &LogRecord{
+		Namespace: target.Pod.Namespace,
+		Pod:       target.Pod.Name,
+		Container: target.Container.Name,
+		Message:   message,
+	}
+
+	for _, p := range target.Processors {
+		if !p.Process(rec) {
+			return "", false
+		}
+	}
+
+	if rec.Rendered != "" {
+		return rec.Rendered, true
+	}
+	return rec.Message, true
+}
@@ -0,0 +1,20 @@
+package command
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ParseRFC3339 parses an RFC3339 timestamp, as accepted by --since-time,
+// into a *metav1.Time.
+func ParseRFC3339(s string) (*metav1.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --since-time %q: %w", s, err)
+	}
+
+	mt := metav1.NewTime(t)
+	return &mt, nil
+}
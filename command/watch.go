@@ -0,0 +1,293 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// watchRetryBackoff is how long reestablishPodWatch waits after a failed
+// re-Watch call before trying again. The first re-Watch after the apiserver
+// closes the pod watch is made immediately.
+const watchRetryBackoff = 2 * time.Second
+
+// watcher reattaches log streams across pod restarts and rolling updates:
+// it watches pods matching the requested target(s) and keeps one stream
+// goroutine alive per (pod UID, container) while the container runs.
+type watcher struct {
+	o        *LogsOptions
+	writer   *prefixWriter
+	colorsOn bool
+
+	streams sync.Map // key -> context.CancelFunc, present while a stream goroutine is live
+	seen    sync.Map // key -> struct{}, present once a container has been attached at least once
+	wg      sync.WaitGroup
+}
+
+// runWatch watches pods matching the CLI targets and streams/reattaches
+// their container logs until ctx is cancelled.
+func (o *LogsOptions) runWatch(ctx context.Context) error {
+	ns := o.namespace()
+
+	match, err := o.buildWatchMatcher(ctx, ns)
+	if err != nil {
+		return err
+	}
+
+	podWatch, err := o.clientset.CoreV1().Pods(ns).Watch(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	defer podWatch.Stop()
+
+	w := &watcher{
+		o:        o,
+		writer:   newPrefixWriter(os.Stdout),
+		colorsOn: colorEnabled(o.Color, os.Stdout),
+	}
+
+	logrus.Infoln("kubelogs watching for pods")
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.wg.Wait()
+			return nil
+
+		case event, ok := <-podWatch.ResultChan():
+			if !ok {
+				podWatch.Stop()
+
+				podWatch, err = o.reestablishPodWatch(ctx, ns)
+				if err != nil {
+					w.wg.Wait()
+					return err
+				}
+				if podWatch == nil {
+					w.wg.Wait()
+					return nil
+				}
+
+				logrus.Infoln("pod watch closed by apiserver, reattached")
+				continue
+			}
+
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok || !match(pod) {
+				continue
+			}
+
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				w.reconcile(ctx, pod)
+			case watch.Deleted:
+				w.cancelPod(pod)
+			}
+		}
+	}
+}
+
+// reestablishPodWatch re-opens the pod watch after the apiserver has closed
+// it, retrying with a fixed backoff after any error (timeouts, apiserver
+// restarts, ...) so a long-running kubelogs --watch survives them instead of
+// exiting. It never returns a non-nil error; it returns a nil
+// watch.Interface (and nil error) if ctx is cancelled while retrying.
+func (o *LogsOptions) reestablishPodWatch(ctx context.Context, ns string) (watch.Interface, error) {
+	for {
+		podWatch, err := o.clientset.CoreV1().Pods(ns).Watch(ctx, metav1.ListOptions{})
+		if err == nil {
+			return podWatch, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+
+		logrus.Errorln("re-establishing pod watch:", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-time.After(watchRetryBackoff):
+		}
+	}
+}
+
+// reconcile starts a stream for every container of pod that has started
+// running and isn't already being streamed.
+func (w *watcher) reconcile(ctx context.Context, pod *corev1.Pod) {
+	o := w.o
+	p := podFromAPI(pod, o.containerName(), o.IncludeInit)
+
+	started := startedContainers(pod)
+
+	for _, container := range p.Containers {
+		if !started[container.Name] {
+			continue
+		}
+
+		key := string(pod.UID) + "/" + container.Name
+		if _, live := w.streams.Load(key); live {
+			continue
+		}
+
+		opts := o.podLogOptions(container.Name)
+		if _, reattach := w.seen.LoadOrStore(key, struct{}{}); reattach {
+			opts.Previous = false
+		}
+
+		disk, err := o.diskWriter(p, container)
+		if err != nil {
+			logrus.Errorln("opening split output for", key, err)
+			continue
+		}
+
+		streamCtx, cancel := context.WithCancel(ctx)
+		w.streams.Store(key, cancel)
+
+		target := &streamTarget{
+			Pod:        p,
+			Container:  container,
+			Opts:       opts,
+			Prefix:     o.containerPrefix(p, container, w.colorsOn),
+			Processors: o.processors,
+			Disk:       disk,
+			Tee:        o.Tee,
+			DiskPrefix: o.diskPrefix(p, container),
+		}
+
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			defer w.streams.Delete(key)
+			streamContainer(streamCtx, o.clientset, target, w.writer)
+		}()
+	}
+}
+
+// cancelPod tears down every live stream for a deleted pod.
+func (w *watcher) cancelPod(pod *corev1.Pod) {
+	prefix := string(pod.UID) + "/"
+	w.streams.Range(func(k, v interface{}) bool {
+		if strings.HasPrefix(k.(string), prefix) {
+			v.(context.CancelFunc)()
+		}
+		return true
+	})
+}
+
+// startedContainers reports, by name, which of pod's containers have run
+// at least once (Running or Terminated), covering main, init, and
+// ephemeral containers.
+func startedContainers(pod *corev1.Pod) map[string]bool {
+	started := make(map[string]bool, len(pod.Status.ContainerStatuses)+len(pod.Status.InitContainerStatuses)+len(pod.Status.EphemeralContainerStatuses))
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		started[cs.Name] = cs.State.Running != nil || cs.State.Terminated != nil
+	}
+	for _, cs := range pod.Status.InitContainerStatuses {
+		started[cs.Name] = cs.State.Running != nil || cs.State.Terminated != nil
+	}
+	for _, cs := range pod.Status.EphemeralContainerStatuses {
+		started[cs.Name] = cs.State.Running != nil || cs.State.Terminated != nil
+	}
+
+	return started
+}
+
+// buildWatchMatcher resolves the CLI targets (selector, TYPE/NAME resources,
+// bare pod-name regexes) into a single predicate tested against every pod
+// the watch observes.
+func (o *LogsOptions) buildWatchMatcher(ctx context.Context, namespace string) (func(*corev1.Pod) bool, error) {
+	var matchers []func(*corev1.Pod) bool
+
+	if o.Selector != "" {
+		sel, err := labels.Parse(o.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --selector %q: %w", o.Selector, err)
+		}
+		matchers = append(matchers, selectorMatcher(sel))
+	}
+
+	for _, arg := range o.args {
+		ref, ok := parseResourceRef(arg)
+		if !ok {
+			pr, err := regexp.Compile(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pod expression %q: %w", arg, err)
+			}
+			matchers = append(matchers, func(p *corev1.Pod) bool { return pr.MatchString(p.Name) })
+			continue
+		}
+
+		if ref.Kind == "cronjob" {
+			m, err := o.cronJobWatchMatcher(ctx, namespace, ref.Name)
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, m)
+			continue
+		}
+
+		sel, err := selectorForResource(ctx, o.clientset, namespace, ref)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, selectorMatcher(sel))
+	}
+
+	return func(p *corev1.Pod) bool {
+		for _, m := range matchers {
+			if m(p) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func selectorMatcher(sel labels.Selector) func(*corev1.Pod) bool {
+	return func(p *corev1.Pod) bool {
+		return sel.Matches(labels.Set(p.Labels))
+	}
+}
+
+// cronJobWatchMatcher matches pods belonging to any Job already owned by
+// the named CronJob at watch-start time. Jobs the CronJob schedules after
+// the watch begins are not known and aren't matched for the rest of the
+// run: the matcher is built once and is not rebuilt when the pod watch is
+// re-established.
+func (o *LogsOptions) cronJobWatchMatcher(ctx context.Context, namespace, name string) (func(*corev1.Pod) bool, error) {
+	cj, err := o.clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := o.clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	jobNames := map[string]bool{}
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if ownedByCronJob(job.OwnerReferences, cj.UID) {
+			jobNames[job.Name] = true
+		}
+	}
+
+	return func(p *corev1.Pod) bool {
+		return jobNames[p.Labels["job-name"]]
+	}, nil
+}
@@ -0,0 +1,186 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// rotatingWriter is an io.WriteCloser over a file on disk that rolls over
+// to path.1, path.2, ... before a write would take it past maxSize bytes
+// (a single write larger than maxSize still goes into an empty file whole).
+// maxSize <= 0 disables rotation.
+type rotatingWriter struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	f        *os.File
+	size     int64
+}
+
+func newRotatingWriter(path string, maxSize int64, maxFiles int) (*rotatingWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	var size int64
+	if info, err := f.Stat(); err == nil {
+		size = info.Size()
+	}
+
+	return &rotatingWriter{path: path, maxSize: maxSize, maxFiles: maxFiles, f: f, size: size}, nil
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate closes the current file, shifts path.1..path.(maxFiles-1) up by
+// one, and reopens path fresh.
+func (w *rotatingWriter) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+
+	if w.maxFiles > 0 {
+		for i := w.maxFiles - 1; i >= 1; i-- {
+			src := fmt.Sprintf("%s.%d", w.path, i)
+			dst := fmt.Sprintf("%s.%d", w.path, i+1)
+			if _, err := os.Stat(src); err == nil {
+				os.Rename(src, dst)
+			}
+		}
+		os.Rename(w.path, w.path+".1")
+	}
+
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	w.f = f
+	w.size = 0
+	return nil
+}
+
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.f.Close()
+}
+
+// sinkRegistry hands out one rotatingWriter per distinct file path,
+// letting several container streams share a single file when --split
+// groups them together.
+type sinkRegistry struct {
+	mu       sync.Mutex
+	writers  map[string]*rotatingWriter
+	maxSize  int64
+	maxFiles int
+}
+
+func newSinkRegistry(maxSize int64, maxFiles int) *sinkRegistry {
+	return &sinkRegistry{writers: make(map[string]*rotatingWriter), maxSize: maxSize, maxFiles: maxFiles}
+}
+
+func (s *sinkRegistry) get(path string) (*rotatingWriter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w, ok := s.writers[path]; ok {
+		return w, nil
+	}
+
+	w, err := newRotatingWriter(path, s.maxSize, s.maxFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	s.writers[path] = w
+	return w, nil
+}
+
+// Close closes every writer handed out so far, returning the first error
+// encountered.
+func (s *sinkRegistry) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for _, w := range s.writers {
+		if err := w.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// splitPath builds the on-disk path for a (namespace, pod, container)
+// stream under the --split granularity.
+func splitPath(outputDir, split, namespace, pod, container string) string {
+	switch split {
+	case "pod":
+		return filepath.Join(outputDir, namespace, pod+".log")
+	case "container":
+		return filepath.Join(outputDir, namespace, container+".log")
+	default: // "pod-container"
+		return filepath.Join(outputDir, namespace, pod, container+".log")
+	}
+}
+
+var sizePattern = regexp.MustCompile(`(?i)^([0-9]*\.?[0-9]+)\s*(b|kb|mb|gb)?$`)
+
+var sizeUnits = map[string]int64{
+	"":   1,
+	"b":  1,
+	"kb": 1 << 10,
+	"mb": 1 << 20,
+	"gb": 1 << 30,
+}
+
+// parseSize parses a human size like "100MB" or "512" (bytes) into a byte
+// count. An empty string means "no limit" (0).
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	m := sizePattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q: expected e.g. 100MB", s)
+	}
+
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+
+	unit, ok := sizeUnits[strings.ToLower(m[2])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit in %q", s)
+	}
+
+	return int64(n * float64(unit)), nil
+}
@@ -0,0 +1,38 @@
+package command
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"512", 512, false},
+		{"100MB", 100 << 20, false},
+		{"1.5GB", int64(1.5 * (1 << 30)), false},
+		{"64kb", 64 << 10, false},
+		{"10b", 10, false},
+		{"not-a-size", 0, true},
+		{"100TB", 0, true},
+	}
+
+	for _, c := range cases {
+		got, err := parseSize(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("parseSize(%q): expected error, got %d", c.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseSize(%q): unexpected error: %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("parseSize(%q) = %d, want %d", c.in, got, c.want)
+		}
+	}
+}